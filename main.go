@@ -2,37 +2,77 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"flag"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"filippo.io/age"
+	sshconfig "github.com/kevinburke/ssh_config"
+	"github.com/zalando/go-keyring"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/term"
 )
 
+// keyringService is the OS keyring service name gss stores remembered
+// passphrases under, keyed by absolute key path.
+const keyringService = "gss"
+
+// ageHeader and keyringConfigMagic let loadConfig auto-detect how
+// config.json was encrypted, if at all.
+const (
+	ageHeader          = "age-encryption.org/v1"
+	keyringConfigMagic = "GSS1"
+)
+
+// HostBlock is one Host alias a key pair answers to in ~/.ssh/config.
+type HostBlock struct {
+	Alias    string `json:"alias"`               // Host pattern, e.g. "github-personal"
+	HostName string `json:"host_name,omitempty"` // HostName, e.g. "github.com"
+	User     string `json:"user,omitempty"`      // User, e.g. "git"
+}
+
 type SSHKeyPairConfig struct {
 	Name           string                 `json:"name"`
 	PrivateKeyPath string                 `json:"private_key_path"`
 	PublicKeyPath  string                 `json:"public_key_path"`
-	SSHConfig      string                 `json:"ssh_config"` // Path to SSH config file
-	GitConfig      map[string]interface{} `json:"git_config"` // Git-specific configuration
+	SSHConfig      string                 `json:"ssh_config"`       // Path to SSH config file
+	Hosts          []HostBlock            `json:"hosts,omitempty"`  // Host aliases this key answers to
+	Scopes         []string               `json:"scopes,omitempty"` // gitdir globs that auto-select this key, e.g. "~/work/**"
+	KeyType        string                 `json:"key_type"`         // rsa, ed25519, or ecdsa
+	Bits           int                    `json:"bits"`             // Key size (RSA/ECDSA) or curve size in bits
+	Fingerprint    string                 `json:"fingerprint"`      // SHA256 fingerprint of the public key
+	GitConfig      map[string]interface{} `json:"git_config"`       // Git-specific configuration
 }
 
 type SSHConfig struct {
-	Keys       []SSHKeyPairConfig `json:"keys"`
-	ActiveKey  int                `json:"active_key"`
-	ConfigPath string             `json:"-"` // Not serialized
-	SSHConfig  string             `json:"-"` // Path to ~/.ssh/config
+	Keys           []SSHKeyPairConfig `json:"keys"`
+	ActiveKey      int                `json:"active_key"`
+	ConfigPath     string             `json:"-"` // Not serialized
+	SSHConfig      string             `json:"-"` // Path to ~/.ssh/config
+	EncryptionMode string             `json:"-"` // "", "age", or "keyring"; detected on load, reused on save
 }
 
 func getHomeDir() string {
@@ -72,6 +112,22 @@ func main() {
 		listCmd(config)
 	case "switch", "s":
 		switchCmd(config)
+	case "agent":
+		agentCmd(config)
+	case "scope":
+		scopeCmd(config)
+	case "apply":
+		applyCmd(config)
+	case "deploy":
+		deployCmd(config)
+	case "undeploy":
+		undeployCmd(config)
+	case "fetch-github":
+		fetchGithubCmd(config)
+	case "init":
+		initCmd(config)
+	case "export":
+		exportCmd(config)
 	case "delete", "del":
 		deleteCmd(config)
 	default:
@@ -87,6 +143,14 @@ func usage() {
 	fmt.Println("  import, i       Import an existing SSH key pair")
 	fmt.Println("  list, ls        List all SSH key pairs")
 	fmt.Println("  switch, s       Switch to an SSH key pair by index, or choose interactively")
+	fmt.Println("  agent           Load a key pair into the running ssh-agent")
+	fmt.Println("  scope           Manage gitdir globs that auto-select a key pair (add, rm)")
+	fmt.Println("  apply           Print GIT_SSH_COMMAND for the key pair scoped to the current directory")
+	fmt.Println("  deploy          Upload a public key to a remote authorized_keys file")
+	fmt.Println("  undeploy        Remove a previously deployed public key from a remote host")
+	fmt.Println("  fetch-github    Import a GitHub user's public keys from github.com/<user>.keys")
+	fmt.Println("  init            Set up gss, optionally enabling encrypted config storage (--encrypt age|keyring)")
+	fmt.Println("  export          Export the full gss state, optionally encrypted (--encrypted)")
 	fmt.Println("  delete, del     Delete an SSH key pair entry from config by index, or choose interactively")
 	fmt.Println("\nUse '<command> -h' for more information on a command.")
 }
@@ -94,7 +158,10 @@ func usage() {
 // Command implementations
 func generateCmd(config *SSHConfig) {
 	generateCmd := flag.NewFlagSet("generate", flag.ExitOnError)
-	name := generateCmd.String("g", "", "Generate a new SSH key pair with given name (RSA)")
+	name := generateCmd.String("g", "", "Generate a new SSH key pair with given name")
+	keyType := generateCmd.String("t", "rsa", "Key type: rsa, ed25519, or ecdsa")
+	bits := generateCmd.Int("b", 0, "Key size in bits (RSA: default 2048; ECDSA: 256, 384, or 521)")
+	withPassphrase := generateCmd.Bool("passphrase", false, "Prompt for a passphrase to encrypt the private key")
 	generateCmd.Parse(os.Args[2:])
 
 	if *name == "" {
@@ -102,7 +169,17 @@ func generateCmd(config *SSHConfig) {
 		os.Exit(1)
 	}
 
-	config.generateKeyPair(*name)
+	var passphrase string
+	if *withPassphrase {
+		p, err := promptPassphrase("Enter passphrase for new private key: ")
+		if err != nil {
+			fmt.Printf("Failed to read passphrase: %v\n", err)
+			os.Exit(1)
+		}
+		passphrase = p
+	}
+
+	config.generateKeyPair(*name, strings.ToLower(*keyType), *bits, passphrase)
 }
 
 func importCmd(config *SSHConfig) {
@@ -113,8 +190,15 @@ func importCmd(config *SSHConfig) {
 	sshConfigPath := importCmd.String("c", "", "Path to SSH config file for imported key pair")
 	email := importCmd.String("git-email", "", "Git user email")
 	nameGit := importCmd.String("git-name", "", "Git user name")
+	encrypted := importCmd.Bool("encrypted", false, "Import a full gss state previously written by 'gss export'")
+	file := importCmd.String("file", "", "Path to the exported state file (used with --encrypted)")
 	importCmd.Parse(os.Args[2:])
 
+	if *encrypted {
+		importStateCmd(config, *file)
+		return
+	}
+
 	if *privPath == "" || *pubPath == "" || *email == "" || *nameGit == "" || *name == "" {
 		importCmd.Usage()
 		os.Exit(1)
@@ -151,6 +235,112 @@ func importCmd(config *SSHConfig) {
 	config.importKeyPair(absPrivPath, absPubPath, *name, absSSHConfigPath, gitConfig)
 }
 
+func initCmd(config *SSHConfig) {
+	initCmd := flag.NewFlagSet("init", flag.ExitOnError)
+	encryptBackend := initCmd.String("encrypt", "", "Enable encrypted config storage: age or keyring")
+	force := initCmd.Bool("force", false, "Regenerate identity/recipients or keyring key even if they already exist")
+	initCmd.Parse(os.Args[2:])
+
+	if *encryptBackend == "" {
+		fmt.Println("gss is initialized.")
+		fmt.Println("Use 'gss init --encrypt age' or 'gss init --encrypt keyring' to encrypt config.json.")
+		return
+	}
+
+	switch *encryptBackend {
+	case "age":
+		if err := setupAgeEncryption(config.ConfigPath, *force); err != nil {
+			fmt.Printf("Failed to set up age encryption: %v\n", err)
+			os.Exit(1)
+		}
+	case "keyring":
+		if err := setupKeyringEncryption(*force); err != nil {
+			fmt.Printf("Failed to set up keyring encryption: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("Unknown encryption backend: %s (use age or keyring)\n", *encryptBackend)
+		os.Exit(1)
+	}
+
+	// saveConfig (deferred in main) will pick this up and migrate
+	// whatever plaintext config.json already exists.
+	config.EncryptionMode = *encryptBackend
+	fmt.Printf("Config encryption enabled (%s). Existing config will be migrated on exit.\n", *encryptBackend)
+}
+
+func exportCmd(config *SSHConfig) {
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	out := exportCmd.String("o", "", "Output file path (defaults to stdout)")
+	encrypted := exportCmd.Bool("encrypted", false, "Encrypt the export using the configured encryption backend")
+	exportCmd.Parse(os.Args[2:])
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		fmt.Printf("Failed to marshal state: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *encrypted {
+		store, err := resolveEncryptedStore(config)
+		if err != nil {
+			fmt.Printf("Failed to export encrypted state: %v\n", err)
+			os.Exit(1)
+		}
+		if data, err = store.Encrypt(data); err != nil {
+			fmt.Printf("Failed to encrypt state: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0600); err != nil {
+		fmt.Printf("Failed to write %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported state to %s\n", *out)
+}
+
+// importStateCmd round-trips a whole gss state previously written by
+// exportCmd, auto-detecting whether it's encrypted.
+func importStateCmd(config *SSHConfig, file string) {
+	if file == "" {
+		fmt.Println("Usage: gss import --encrypted --file <path>")
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Printf("Failed to read %s: %v\n", file, err)
+		os.Exit(1)
+	}
+
+	if mode := detectEncryption(data); mode != "" {
+		store, err := newEncryptedStore(mode, config.ConfigPath)
+		if err != nil {
+			fmt.Printf("Failed to load encryption backend for import: %v\n", err)
+			os.Exit(1)
+		}
+		if data, err = store.Decrypt(data); err != nil {
+			fmt.Printf("Failed to decrypt %s: %v\n", file, err)
+			os.Exit(1)
+		}
+	}
+
+	var imported SSHConfig
+	if err := json.Unmarshal(data, &imported); err != nil {
+		fmt.Printf("Failed to parse exported state: %v\n", err)
+		os.Exit(1)
+	}
+
+	config.Keys = imported.Keys
+	config.ActiveKey = imported.ActiveKey
+	fmt.Printf("Imported state from %s (%d key pair(s))\n", file, len(imported.Keys))
+}
+
 func listCmd(config *SSHConfig) {
 	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
 	listCmd.Parse(os.Args[2:])
@@ -205,34 +395,57 @@ func switchCmd(config *SSHConfig) {
 	config.ActiveKey = chosenIndex
 	key := config.Keys[chosenIndex]
 
+	// SSH configuration
+	if err := os.MkdirAll(filepath.Dir(config.SSHConfig), 0700); err != nil {
+		fmt.Printf("Failed to create SSH config directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	existingData, err := os.ReadFile(config.SSHConfig)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Failed to read SSH config: %v\n", err)
+		os.Exit(1)
+	}
+	existingContent := string(existingData)
+
+	identityFile, err := resolveIdentityFile(key, existingContent)
+	if err != nil {
+		fmt.Printf("Failed to resolve identity file: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Adjust private key permissions for Linux/Unix
-	privKeyPath := key.PrivateKeyPath
-	if _, err := os.Stat(privKeyPath); os.IsNotExist(err) {
-		fmt.Printf("Private key not found at: %s\n", privKeyPath)
+	if _, err := os.Stat(identityFile); os.IsNotExist(err) {
+		fmt.Printf("Private key not found at: %s\n", identityFile)
 		os.Exit(1)
 	}
 
 	if runtime.GOOS != "windows" { // Linux/Unix
-		fmt.Printf("Adjusting permissions for private key (Linux/Unix): %s\n", privKeyPath)
-		if err := os.Chmod(privKeyPath, 0600); err != nil {
+		fmt.Printf("Adjusting permissions for private key (Linux/Unix): %s\n", identityFile)
+		if err := os.Chmod(identityFile, 0600); err != nil {
 			fmt.Printf("Failed to set private key permissions to 0600: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Println("Private key permissions set to 0600 on Linux/Unix.")
 	}
 
-	// SSH configuration
-	if err := os.MkdirAll(filepath.Dir(config.SSHConfig), 0700); err != nil {
-		fmt.Printf("Failed to create SSH config directory: %v\n", err)
-		os.Exit(1)
-	}
+	fmt.Printf("Using IdentityFile: %s\n", identityFile)
 
-	fmt.Printf("Using IdentityFile: %s\n", key.PrivateKeyPath)
+	hosts := key.Hosts
+	if len(hosts) == 0 {
+		hosts = []HostBlock{{Alias: key.Name}}
+	}
 
-	// Predefined configuration with dynamic IdentityFile
-	predefinedConfig := fmt.Sprintf("IdentityFile %s", key.PrivateKeyPath)
+	configContent := existingContent
+	for _, hb := range hosts {
+		marker := hb.Alias
+		if marker == "" {
+			marker = key.Name
+		}
+		configContent = upsertManagedBlock(configContent, marker, renderHostBlock(marker, hb, identityFile))
+	}
 
-	var configContent string
+	// Legacy per-key SSH config file, folded in as its own managed section
 	if key.SSHConfig != "" {
 		absSSHConfigPath, err := filepath.Abs(key.SSHConfig)
 		if err != nil {
@@ -243,21 +456,23 @@ func switchCmd(config *SSHConfig) {
 			fmt.Printf("SSH config file not found: %s\n", absSSHConfigPath)
 			os.Exit(1)
 		}
-		configData, err := os.ReadFile(absSSHConfigPath)
+		extraData, err := os.ReadFile(absSSHConfigPath)
 		if err != nil {
 			fmt.Printf("Failed to read SSH config file: %v\n", err)
 			os.Exit(1)
 		}
-		configContent = predefinedConfig + "\n" + string(configData)
-	} else {
-		configContent = predefinedConfig
+		configContent = upsertManagedBlock(configContent, key.Name+"-include", string(extraData))
 	}
 
 	if err := os.WriteFile(config.SSHConfig, []byte(configContent), 0600); err != nil {
 		fmt.Printf("Failed to update SSH config: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("SSH key switched to: %s (%s)\n", key.Name, key.PrivateKeyPath)
+	fmt.Printf("SSH key switched to: %s (%s)\n", key.Name, identityFile)
+
+	if err := loadKeyIntoAgent(key, identityFile, false); err != nil {
+		fmt.Printf("Note: could not load key into ssh-agent: %v\n", err)
+	}
 
 	// Git configuration
 	var gitConfigFile string
@@ -319,10 +534,10 @@ func switchCmd(config *SSHConfig) {
 		fmt.Printf("\n--- Important: Manual Permissions Adjustment Required (Windows) ---\n")
 		fmt.Printf("Please run the following commands in an **Administrator Command Prompt (CMD)**:\n\n")
 
-		fmt.Printf(":: For your Private Key: %s\n", privKeyPath)
-		fmt.Printf("icacls \"%s\" /reset\n", privKeyPath)
-		fmt.Printf("icacls \"%s\" /grant:r \"%s\":F\n", privKeyPath, currentUser)
-		fmt.Printf("icacls \"%s\" /inheritance:r\n\n", privKeyPath)
+		fmt.Printf(":: For your Private Key: %s\n", identityFile)
+		fmt.Printf("icacls \"%s\" /reset\n", identityFile)
+		fmt.Printf("icacls \"%s\" /grant:r \"%s\":F\n", identityFile, currentUser)
+		fmt.Printf("icacls \"%s\" /inheritance:r\n\n", identityFile)
 
 		sshConfigFilePath := config.SSHConfig
 		fmt.Printf(":: For your SSH Config File: %s\n", sshConfigFilePath)
@@ -335,42 +550,26 @@ func switchCmd(config *SSHConfig) {
 	}
 }
 
-func deleteCmd(config *SSHConfig) {
-	deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
+func agentCmd(config *SSHConfig) {
+	agentCmd := flag.NewFlagSet("agent", flag.ExitOnError)
 	indexProvided := false
-	indexVal := deleteCmd.Int("i", -1, "Index of SSH key pair to delete from config")
-	force := deleteCmd.Bool("f", false, "Force deletion without confirmation")
-	deleteCmd.Visit(func(f *flag.Flag) {
+	indexVal := agentCmd.Int("i", -1, "Index of SSH key pair to load into ssh-agent")
+	remember := agentCmd.Bool("remember", false, "Persist the passphrase in the OS keyring so future loads don't prompt")
+	agentCmd.Visit(func(f *flag.Flag) {
 		if f.Name == "i" {
 			indexProvided = true
 		}
 	})
-
-	deleteCmd.Parse(os.Args[2:])
+	agentCmd.Parse(os.Args[2:])
 
 	if len(config.Keys) == 0 {
-		fmt.Println("No key pairs found to delete. Generate or import one first.")
+		fmt.Println("No key pairs found. Generate or import one first.")
 		os.Exit(1)
 	}
 
-	var chosenIndex int
+	chosenIndex := config.ActiveKey
 	if indexProvided {
 		chosenIndex = *indexVal
-	} else {
-		// Interactive mode
-		fmt.Println("\n--- Available SSH Key Pairs for Deletion ---")
-		config.listKeyPairs()
-		fmt.Print("Enter the index of the key pair to delete: ")
-
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		parsedIndex, err := strconv.Atoi(input)
-		if err != nil {
-			fmt.Printf("Invalid input. Please enter a number.\n")
-			os.Exit(1)
-		}
-		chosenIndex = parsedIndex
 	}
 
 	if chosenIndex < 0 || chosenIndex >= len(config.Keys) {
@@ -379,164 +578,1047 @@ func deleteCmd(config *SSHConfig) {
 	}
 
 	key := config.Keys[chosenIndex]
-	fmt.Printf("\nSelected key pair for deletion from config:\n")
-	fmt.Printf("  Name: %s\n", key.Name)
-	fmt.Printf("  Private Key: %s\n", key.PrivateKeyPath)
-	fmt.Printf("  Public Key: %s\n", key.PublicKeyPath)
-	if key.SSHConfig != "" {
-		fmt.Printf("  SSH Config: %s\n", key.SSHConfig)
+	existingData, _ := os.ReadFile(config.SSHConfig)
+	identityFile, err := resolveIdentityFile(key, string(existingData))
+	if err != nil {
+		fmt.Printf("Failed to resolve identity file: %v\n", err)
+		os.Exit(1)
 	}
 
-	if !*force {
-		fmt.Print("\nAre you sure you want to delete this key pair entry from config? (Files will not be deleted) (y/N): ")
-		reader := bufio.NewReader(os.Stdin)
-		confirmation, _ := reader.ReadString('\n')
-		confirmation = strings.TrimSpace(strings.ToLower(confirmation))
-		if confirmation != "y" && confirmation != "yes" {
-			fmt.Println("Deletion cancelled.")
-			os.Exit(0)
-		}
+	if err := loadKeyIntoAgent(key, identityFile, *remember); err != nil {
+		fmt.Printf("Failed to load key into ssh-agent: %v\n", err)
+		os.Exit(1)
 	}
+}
 
-	// Remove from config
-	config.Keys = append(config.Keys[:chosenIndex], config.Keys[chosenIndex+1:]...)
-
-	// Adjust ActiveKey if necessary
-	if config.ActiveKey == chosenIndex {
-		config.ActiveKey = -1
-		if len(config.Keys) > 0 {
-			config.ActiveKey = 0
-			fmt.Printf("Active key was deleted. Switched to key pair: %s (Index: 0)\n", config.Keys[0].Name)
-		} else {
-			fmt.Println("No key pairs remaining. Active key unset.")
-			// Clear SSH config if no keys remain
-			if err := os.WriteFile(config.SSHConfig, []byte(""), 0600); err != nil {
-				fmt.Printf("Failed to clear SSH config: %v\n", err)
-			}
-		}
-	} else if config.ActiveKey > chosenIndex {
-		config.ActiveKey--
+func scopeCmd(config *SSHConfig) {
+	if len(os.Args) < 3 {
+		fmt.Println("Usage: gss scope <add|rm> -i <idx> <glob>")
+		os.Exit(1)
 	}
 
-	fmt.Printf("Successfully deleted key pair entry: %s (Index: %d) from config\n", key.Name, chosenIndex)
+	switch os.Args[2] {
+	case "add":
+		scopeAddCmd(config)
+	case "rm":
+		scopeRmCmd(config)
+	default:
+		fmt.Printf("Unknown scope subcommand: %s\n", os.Args[2])
+		fmt.Println("Usage: gss scope <add|rm> -i <idx> <glob>")
+		os.Exit(1)
+	}
 }
 
-func (c *SSHConfig) loadConfig() {
-	configFile := filepath.Join(c.ConfigPath, "config.json")
-	data, err := os.ReadFile(configFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			c.Keys = []SSHKeyPairConfig{}
-			return
-		}
-		fmt.Printf("Failed to read config file: %v\n", err)
+func scopeAddCmd(config *SSHConfig) {
+	scopeAddCmd := flag.NewFlagSet("scope add", flag.ExitOnError)
+	idx := scopeAddCmd.Int("i", -1, "Index of SSH key pair to scope")
+	scopeAddCmd.Parse(os.Args[3:])
+
+	if *idx < 0 || *idx >= len(config.Keys) {
+		fmt.Printf("Invalid index: %d (available: 0 to %d)\n", *idx, len(config.Keys)-1)
+		os.Exit(1)
+	}
+	glob := scopeAddCmd.Arg(0)
+	if glob == "" {
+		fmt.Println("Usage: gss scope add -i <idx> <glob>")
 		os.Exit(1)
 	}
 
-	if err := json.Unmarshal(data, c); err != nil {
-		fmt.Printf("Failed to parse config file: %v\n", err)
+	key := &config.Keys[*idx]
+	key.Scopes = append(key.Scopes, glob)
+
+	if err := applyScope(config, *key, glob); err != nil {
+		fmt.Printf("Failed to apply scope %q: %v\n", glob, err)
 		os.Exit(1)
 	}
+
+	fmt.Printf("Key pair %s will now be used automatically under %s\n", key.Name, glob)
 }
 
-func (c *SSHConfig) saveConfig() {
-	configFile := filepath.Join(c.ConfigPath, "config.json")
-	data, err := json.MarshalIndent(c, "", "  ")
-	if err != nil {
-		fmt.Printf("Failed to marshal config: %v\n", err)
+func scopeRmCmd(config *SSHConfig) {
+	scopeRmCmd := flag.NewFlagSet("scope rm", flag.ExitOnError)
+	idx := scopeRmCmd.Int("i", -1, "Index of SSH key pair to unscope")
+	scopeRmCmd.Parse(os.Args[3:])
+
+	if *idx < 0 || *idx >= len(config.Keys) {
+		fmt.Printf("Invalid index: %d (available: 0 to %d)\n", *idx, len(config.Keys)-1)
+		os.Exit(1)
+	}
+	glob := scopeRmCmd.Arg(0)
+	if glob == "" {
+		fmt.Println("Usage: gss scope rm -i <idx> <glob>")
 		os.Exit(1)
 	}
 
-	if err := os.WriteFile(configFile, data, 0600); err != nil {
-		fmt.Printf("Failed to write config file: %v\n", err)
+	key := &config.Keys[*idx]
+	remaining := key.Scopes[:0]
+	removed := false
+	for _, s := range key.Scopes {
+		if s == glob {
+			removed = true
+			continue
+		}
+		remaining = append(remaining, s)
+	}
+	key.Scopes = remaining
+
+	if !removed {
+		fmt.Printf("Scope %q not found on key pair %s\n", glob, key.Name)
 		os.Exit(1)
 	}
-}
 
-func (c *SSHConfig) generateKeyPair(name string) {
-	if name == "" {
-		name = "id_rsa"
+	cmd := exec.Command("git", "config", "--global", "--unset", fmt.Sprintf("includeIf.gitdir:%s.path", glob))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("Warning: failed to remove includeIf entry for %q: %v\n", glob, err)
 	}
 
-	privPath, pubPath := getUniqueFilePaths(c.ConfigPath, name)
+	fmt.Printf("Removed scope %q from key pair %s\n", glob, key.Name)
+}
 
-	// Convert to absolute paths
-	absPrivPath, err := filepath.Abs(privPath)
+// applyScope writes the per-key ssh_config and git include file for glob and
+// registers a `git config --global includeIf.gitdir:<glob>.path` entry
+// pointing at it, so repos under glob pick up key's identity automatically.
+func applyScope(config *SSHConfig, key SSHKeyPairConfig, glob string) error {
+	existingData, _ := os.ReadFile(config.SSHConfig)
+	identityFile, err := resolveIdentityFile(key, string(existingData))
 	if err != nil {
-		fmt.Printf("Failed to convert private key path to absolute: %v\n", err)
-		os.Exit(1)
+		return err
 	}
-	absPubPath, err := filepath.Abs(pubPath)
-	if err != nil {
-		fmt.Printf("Failed to convert public key path to absolute: %v\n", err)
-		os.Exit(1)
+
+	sshConfigPath := keyScopedSSHConfigPath(config, key)
+	hosts := key.Hosts
+	if len(hosts) == 0 {
+		hosts = []HostBlock{{Alias: "*"}}
+	}
+	var sshContent string
+	for _, hb := range hosts {
+		marker := hb.Alias
+		if marker == "" {
+			marker = key.Name
+		}
+		sshContent = upsertManagedBlock(sshContent, marker, renderHostBlock(marker, hb, identityFile))
+	}
+	if err := os.WriteFile(sshConfigPath, []byte(sshContent), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sshConfigPath, err)
 	}
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		fmt.Printf("Failed to generate private key: %v\n", err)
-		os.Exit(1)
+	var emailVal, nameVal string
+	if v, ok := key.GitConfig["user.email"].(string); ok {
+		emailVal = v
+	}
+	if v, ok := key.GitConfig["user.name"].(string); ok {
+		nameVal = v
 	}
 
-	privFile, err := os.OpenFile(absPrivPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		fmt.Printf("Failed to create private key file: %v\n", err)
-		os.Exit(1)
+	var include strings.Builder
+	if emailVal != "" || nameVal != "" {
+		include.WriteString("[user]\n")
+		if nameVal != "" {
+			fmt.Fprintf(&include, "\tname = %s\n", nameVal)
+		}
+		if emailVal != "" {
+			fmt.Fprintf(&include, "\temail = %s\n", emailVal)
+		}
 	}
-	defer privFile.Close()
+	fmt.Fprintf(&include, "[core]\n\tsshCommand = ssh -F %s\n", sshConfigPath)
 
-	privPEM := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	includePath := keyIncludeGitConfigPath(config, key)
+	if err := os.WriteFile(includePath, []byte(include.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", includePath, err)
 	}
-	if err := pem.Encode(privFile, privPEM); err != nil {
-		fmt.Printf("Failed to write private key: %v\n", err)
-		os.Exit(1)
+
+	cmd := exec.Command("git", "config", "--global", fmt.Sprintf("includeIf.gitdir:%s.path", glob), includePath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to register includeIf in ~/.gitconfig: %w", err)
 	}
+	return nil
+}
 
-	pubKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
-	if err != nil {
-		fmt.Printf("Failed to generate public key: %v\n", err)
-		os.Exit(1)
+func keyScopedSSHConfigPath(config *SSHConfig, key SSHKeyPairConfig) string {
+	return filepath.Join(config.ConfigPath, "ssh_config_"+key.Name)
+}
+
+func keyIncludeGitConfigPath(config *SSHConfig, key SSHKeyPairConfig) string {
+	return filepath.Join(config.ConfigPath, "include-"+key.Name+".gitconfig")
+}
+
+// scopeMatches mirrors git's includeIf "gitdir:" matching closely enough for
+// our purposes: a trailing "/**" matches the prefix dir and everything
+// beneath it, anything else is matched with filepath.Match.
+func scopeMatches(glob, dir string) bool {
+	glob = expandHome(glob)
+	dir = filepath.Clean(dir)
+
+	if strings.HasSuffix(glob, "/**") {
+		prefix := filepath.Clean(strings.TrimSuffix(glob, "/**"))
+		return dir == prefix || strings.HasPrefix(dir, prefix+string(filepath.Separator))
 	}
-	pubBytes := ssh.MarshalAuthorizedKey(pubKey)
 
-	pubFile, err := os.OpenFile(absPubPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	matched, _ := filepath.Match(filepath.Clean(glob), dir)
+	return matched
+}
+
+func applyCmd(config *SSHConfig) {
+	applyCmd := flag.NewFlagSet("apply", flag.ExitOnError)
+	applyCmd.Parse(os.Args[2:])
+
+	cwd, err := os.Getwd()
 	if err != nil {
-		fmt.Printf("Failed to create public key file: %v\n", err)
+		fmt.Printf("Failed to determine current directory: %v\n", err)
 		os.Exit(1)
 	}
-	defer pubFile.Close()
 
-	if _, err := pubFile.Write(pubBytes); err != nil {
-		fmt.Printf("Failed to write public key: %v\n", err)
-		os.Exit(1)
+	for _, key := range config.Keys {
+		for _, scope := range key.Scopes {
+			if !scopeMatches(scope, cwd) {
+				continue
+			}
+			fmt.Printf("export GIT_SSH_COMMAND=\"ssh -F %s\"\n", keyScopedSSHConfigPath(config, key))
+			return
+		}
 	}
 
-	fmt.Printf("Generated: %s (%s, %s)\n", name, absPrivPath, absPubPath)
-	c.Keys = append(c.Keys, SSHKeyPairConfig{
-		Name:           name,
-		PrivateKeyPath: absPrivPath,
-		PublicKeyPath:  absPubPath,
-		SSHConfig:      "",
-		GitConfig:      make(map[string]interface{}),
-	})
+	fmt.Printf("# no gss scope matched %s\n", cwd)
 }
 
-func (c *SSHConfig) importKeyPair(privPath, pubPath, name, sshConfigPath string, gitConfig map[string]interface{}) {
-	if _, err := os.Stat(privPath); os.IsNotExist(err) {
-		fmt.Printf("Private key not found: %s\n", privPath)
-		os.Exit(1)
-	}
-	if _, err := os.Stat(pubPath); os.IsNotExist(err) {
-		fmt.Printf("Public key not found: %s\n", pubPath)
+func deployCmd(config *SSHConfig) {
+	deployCmd := flag.NewFlagSet("deploy", flag.ExitOnError)
+	idx := deployCmd.Int("i", -1, "Index of SSH key pair to deploy")
+	hostsFile := deployCmd.String("hosts-file", "", "Path to a file with one user@host[:port] target per line")
+	deployCmd.Parse(os.Args[2:])
+
+	if *idx < 0 || *idx >= len(config.Keys) {
+		fmt.Printf("Invalid index: %d (available: 0 to %d)\n", *idx, len(config.Keys)-1)
 		os.Exit(1)
 	}
 
-	// Read private key to validate
-	privData, err := os.ReadFile(privPath)
+	targets, err := resolveTargets(deployCmd.Args(), *hostsFile)
 	if err != nil {
-		fmt.Printf("Failed to read private key: %v\n", err)
+		fmt.Printf("Failed to read hosts file: %v\n", err)
+		os.Exit(1)
+	}
+	if len(targets) == 0 {
+		fmt.Println("Usage: gss deploy -i <idx> [--hosts-file <path>] [user@host[:port] ...]")
+		os.Exit(1)
+	}
+
+	key := config.Keys[*idx]
+	failures := 0
+	for _, target := range targets {
+		if err := deployKeyToHost(key, target); err != nil {
+			fmt.Printf("Failed to deploy to %s: %v\n", target, err)
+			failures++
+		}
+	}
+	if failures > 0 {
+		os.Exit(1)
+	}
+}
+
+func undeployCmd(config *SSHConfig) {
+	undeployCmd := flag.NewFlagSet("undeploy", flag.ExitOnError)
+	idx := undeployCmd.Int("i", -1, "Index of SSH key pair to undeploy")
+	hostsFile := undeployCmd.String("hosts-file", "", "Path to a file with one user@host[:port] target per line")
+	undeployCmd.Parse(os.Args[2:])
+
+	if *idx < 0 || *idx >= len(config.Keys) {
+		fmt.Printf("Invalid index: %d (available: 0 to %d)\n", *idx, len(config.Keys)-1)
+		os.Exit(1)
+	}
+
+	targets, err := resolveTargets(undeployCmd.Args(), *hostsFile)
+	if err != nil {
+		fmt.Printf("Failed to read hosts file: %v\n", err)
+		os.Exit(1)
+	}
+	if len(targets) == 0 {
+		fmt.Println("Usage: gss undeploy -i <idx> [--hosts-file <path>] [user@host[:port] ...]")
+		os.Exit(1)
+	}
+
+	key := config.Keys[*idx]
+	failures := 0
+	for _, target := range targets {
+		if err := undeployKeyFromHost(key, target); err != nil {
+			fmt.Printf("Failed to undeploy from %s: %v\n", target, err)
+			failures++
+		}
+	}
+	if failures > 0 {
+		os.Exit(1)
+	}
+}
+
+// resolveTargets merges user@host[:port] targets passed as positional
+// arguments with one-per-line targets read from hostsFile, if given.
+func resolveTargets(explicit []string, hostsFile string) ([]string, error) {
+	var targets []string
+	if hostsFile != "" {
+		data, err := os.ReadFile(hostsFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			targets = append(targets, line)
+		}
+	}
+	targets = append(targets, explicit...)
+	return targets, nil
+}
+
+// dialSSHTarget opens an SSH connection to a "user@host[:port]" target,
+// authenticating with whichever signer is available first: a live
+// ssh-agent, then key's own private key. Host keys are checked against
+// ~/.ssh/known_hosts.
+func dialSSHTarget(key SSHKeyPairConfig, target string) (*ssh.Client, error) {
+	atIdx := strings.Index(target, "@")
+	if atIdx < 0 {
+		return nil, fmt.Errorf("target %q must be in user@host[:port] form", target)
+	}
+	user := target[:atIdx]
+	hostPort := target[atIdx+1:]
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host, port = hostPort, "22"
+	}
+
+	var authMethods []ssh.AuthMethod
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			ag := agent.NewClient(conn)
+			authMethods = append(authMethods, ssh.PublicKeysCallback(ag.Signers))
+		}
+	}
+	if key.PrivateKeyPath != "" {
+		if ck, err := loadPrivateKey(key.PrivateKeyPath, false); err == nil {
+			authMethods = append(authMethods, ssh.PublicKeys(ck.signer))
+		}
+	}
+	if len(authMethods) == 0 {
+		return nil, fmt.Errorf("no available authentication method (no ssh-agent and no usable private key)")
+	}
+
+	knownHostsPath := filepath.Join(getHomeDir(), ".ssh", "known_hosts")
+	if err := os.MkdirAll(filepath.Dir(knownHostsPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create ~/.ssh: %w", err)
+	}
+	// deploy is often the first thing to ever talk to a given host, so create
+	// an empty known_hosts rather than requiring the user ssh in manually first.
+	f, err := os.OpenFile(knownHostsPath, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create known_hosts: %w", err)
+	}
+	f.Close()
+
+	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User:            user,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         10 * time.Second,
+	}
+
+	return ssh.Dial("tcp", net.JoinHostPort(host, port), clientConfig)
+}
+
+// runRemoteCommand runs cmd on client, optionally feeding stdin, and returns
+// its combined stdout.
+func runRemoteCommand(client *ssh.Client, cmd, stdin string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to open session: %w", err)
+	}
+	defer session.Close()
+
+	if stdin != "" {
+		session.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	if err := session.Run(cmd); err != nil {
+		return "", fmt.Errorf("%s: %w (%s)", cmd, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// deployKeyToHost uploads key's public key to target's ~/.ssh/authorized_keys,
+// deduplicating by key body and tagging the appended line with
+// "# gss:<name>:<fingerprint>" so undeployKeyFromHost can remove exactly what
+// was added.
+func deployKeyToHost(key SSHKeyPairConfig, target string) error {
+	client, err := dialSSHTarget(key, target)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	pubData, err := os.ReadFile(key.PublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+	pubLine := strings.TrimSpace(string(pubData))
+	pubKey, _, _, _, err := ssh.ParseAuthorizedKey(pubData)
+	if err != nil {
+		return fmt.Errorf("failed to parse public key: %w", err)
+	}
+	keyBody := strings.Join(strings.Fields(pubLine)[:2], " ")
+	tag := fmt.Sprintf("# gss:%s:%s", key.Name, ssh.FingerprintSHA256(pubKey))
+
+	if _, err := runRemoteCommand(client, "mkdir -p ~/.ssh && chmod 700 ~/.ssh", ""); err != nil {
+		return err
+	}
+	existing, err := runRemoteCommand(client, "cat ~/.ssh/authorized_keys 2>/dev/null || true", "")
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(existing, "\n") {
+		if strings.Contains(line, keyBody) {
+			fmt.Printf("Key %s already present on %s, skipping.\n", key.Name, target)
+			return nil
+		}
+	}
+
+	updated := strings.TrimRight(existing, "\n")
+	if updated != "" {
+		updated += "\n"
+	}
+	updated += pubLine + " " + tag + "\n"
+
+	if _, err := runRemoteCommand(client, "cat > ~/.ssh/authorized_keys && chmod 600 ~/.ssh/authorized_keys", updated); err != nil {
+		return fmt.Errorf("failed to update authorized_keys: %w", err)
+	}
+
+	fmt.Printf("Deployed key %s to %s\n", key.Name, target)
+	return nil
+}
+
+// undeployKeyFromHost removes exactly the lines deployKeyToHost tagged with
+// key's name from target's ~/.ssh/authorized_keys.
+func undeployKeyFromHost(key SSHKeyPairConfig, target string) error {
+	client, err := dialSSHTarget(key, target)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	existing, err := runRemoteCommand(client, "cat ~/.ssh/authorized_keys 2>/dev/null || true", "")
+	if err != nil {
+		return err
+	}
+
+	tagPrefix := fmt.Sprintf("# gss:%s:", key.Name)
+	var kept []string
+	removed := false
+	for _, line := range strings.Split(existing, "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, tagPrefix) {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	if !removed {
+		fmt.Printf("No gss-managed entry for %s found on %s\n", key.Name, target)
+		return nil
+	}
+
+	updated := strings.Join(kept, "\n")
+	if updated != "" {
+		updated += "\n"
+	}
+	if _, err := runRemoteCommand(client, "cat > ~/.ssh/authorized_keys && chmod 600 ~/.ssh/authorized_keys", updated); err != nil {
+		return fmt.Errorf("failed to update authorized_keys: %w", err)
+	}
+
+	fmt.Printf("Removed key %s from %s\n", key.Name, target)
+	return nil
+}
+
+// fetchGithubCmd pulls a GitHub user's public keys from github.com/<user>.keys
+// and registers each as a public-key-only import entry.
+func fetchGithubCmd(config *SSHConfig) {
+	fetchGithubCmd := flag.NewFlagSet("fetch-github", flag.ExitOnError)
+	fetchGithubCmd.Parse(os.Args[2:])
+
+	user := fetchGithubCmd.Arg(0)
+	if user == "" {
+		fmt.Println("Usage: gss fetch-github <user>")
+		os.Exit(1)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("https://github.com/%s.keys", user))
+	if err != nil {
+		fmt.Printf("Failed to fetch keys for %s: %v\n", user, err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("Failed to fetch keys for %s: unexpected status %s\n", user, resp.Status)
+		os.Exit(1)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Printf("Failed to read response: %v\n", err)
+		os.Exit(1)
+	}
+
+	added := 0
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		name := fmt.Sprintf("github-%s-%d", user, i+1)
+		pubPath := filepath.Join(config.ConfigPath, name+".pub")
+		if err := os.WriteFile(pubPath, []byte(line+"\n"), 0644); err != nil {
+			fmt.Printf("Failed to write %s: %v\n", pubPath, err)
+			continue
+		}
+
+		var fingerprint string
+		if pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line)); err == nil {
+			fingerprint = ssh.FingerprintSHA256(pubKey)
+		}
+
+		config.Keys = append(config.Keys, SSHKeyPairConfig{
+			Name:          name,
+			PublicKeyPath: pubPath,
+			Fingerprint:   fingerprint,
+			GitConfig:     make(map[string]interface{}),
+		})
+		added++
+	}
+
+	fmt.Printf("Imported %d public key(s) from github.com/%s\n", added, user)
+}
+
+func deleteCmd(config *SSHConfig) {
+	deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
+	indexProvided := false
+	indexVal := deleteCmd.Int("i", -1, "Index of SSH key pair to delete from config")
+	force := deleteCmd.Bool("f", false, "Force deletion without confirmation")
+	deleteCmd.Visit(func(f *flag.Flag) {
+		if f.Name == "i" {
+			indexProvided = true
+		}
+	})
+
+	deleteCmd.Parse(os.Args[2:])
+
+	if len(config.Keys) == 0 {
+		fmt.Println("No key pairs found to delete. Generate or import one first.")
+		os.Exit(1)
+	}
+
+	var chosenIndex int
+	if indexProvided {
+		chosenIndex = *indexVal
+	} else {
+		// Interactive mode
+		fmt.Println("\n--- Available SSH Key Pairs for Deletion ---")
+		config.listKeyPairs()
+		fmt.Print("Enter the index of the key pair to delete: ")
+
+		reader := bufio.NewReader(os.Stdin)
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		parsedIndex, err := strconv.Atoi(input)
+		if err != nil {
+			fmt.Printf("Invalid input. Please enter a number.\n")
+			os.Exit(1)
+		}
+		chosenIndex = parsedIndex
+	}
+
+	if chosenIndex < 0 || chosenIndex >= len(config.Keys) {
+		fmt.Printf("Invalid index: %d (available: 0 to %d)\n", chosenIndex, len(config.Keys)-1)
+		os.Exit(1)
+	}
+
+	key := config.Keys[chosenIndex]
+	fmt.Printf("\nSelected key pair for deletion from config:\n")
+	fmt.Printf("  Name: %s\n", key.Name)
+	fmt.Printf("  Private Key: %s\n", key.PrivateKeyPath)
+	fmt.Printf("  Public Key: %s\n", key.PublicKeyPath)
+	if key.SSHConfig != "" {
+		fmt.Printf("  SSH Config: %s\n", key.SSHConfig)
+	}
+
+	if !*force {
+		fmt.Print("\nAre you sure you want to delete this key pair entry from config? (Files will not be deleted) (y/N): ")
+		reader := bufio.NewReader(os.Stdin)
+		confirmation, _ := reader.ReadString('\n')
+		confirmation = strings.TrimSpace(strings.ToLower(confirmation))
+		if confirmation != "y" && confirmation != "yes" {
+			fmt.Println("Deletion cancelled.")
+			os.Exit(0)
+		}
+	}
+
+	// Remove from config
+	config.Keys = append(config.Keys[:chosenIndex], config.Keys[chosenIndex+1:]...)
+
+	// Adjust ActiveKey if necessary
+	if config.ActiveKey == chosenIndex {
+		config.ActiveKey = -1
+		if len(config.Keys) > 0 {
+			config.ActiveKey = 0
+			fmt.Printf("Active key was deleted. Switched to key pair: %s (Index: 0)\n", config.Keys[0].Name)
+		} else {
+			fmt.Println("No key pairs remaining. Active key unset.")
+			// Clear SSH config if no keys remain
+			if err := os.WriteFile(config.SSHConfig, []byte(""), 0600); err != nil {
+				fmt.Printf("Failed to clear SSH config: %v\n", err)
+			}
+		}
+	} else if config.ActiveKey > chosenIndex {
+		config.ActiveKey--
+	}
+
+	fmt.Printf("Successfully deleted key pair entry: %s (Index: %d) from config\n", key.Name, chosenIndex)
+}
+
+func (c *SSHConfig) loadConfig() {
+	configFile := filepath.Join(c.ConfigPath, "config.json")
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.Keys = []SSHKeyPairConfig{}
+			return
+		}
+		fmt.Printf("Failed to read config file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if mode := detectEncryption(data); mode != "" {
+		store, err := newEncryptedStore(mode, c.ConfigPath)
+		if err != nil {
+			fmt.Printf("Failed to load config encryption backend: %v\n", err)
+			os.Exit(1)
+		}
+		plain, err := store.Decrypt(data)
+		if err != nil {
+			fmt.Printf("Failed to decrypt config file: %v\n", err)
+			os.Exit(1)
+		}
+		data = plain
+		c.EncryptionMode = mode
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		fmt.Printf("Failed to parse config file: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (c *SSHConfig) saveConfig() {
+	configFile := filepath.Join(c.ConfigPath, "config.json")
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		fmt.Printf("Failed to marshal config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if c.EncryptionMode != "" {
+		store, err := resolveEncryptedStore(c)
+		if err != nil {
+			fmt.Printf("Failed to load config encryption backend: %v\n", err)
+			os.Exit(1)
+		}
+		if data, err = store.Encrypt(data); err != nil {
+			fmt.Printf("Failed to encrypt config: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := os.WriteFile(configFile, data, 0600); err != nil {
+		fmt.Printf("Failed to write config file: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// EncryptedStore seals and opens the bytes of config.json.
+type EncryptedStore interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// detectEncryption returns which EncryptedStore (if any) can open data.
+func detectEncryption(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte(ageHeader)):
+		return "age"
+	case bytes.HasPrefix(data, []byte(keyringConfigMagic)):
+		return "keyring"
+	default:
+		return ""
+	}
+}
+
+func newEncryptedStore(mode, configDir string) (EncryptedStore, error) {
+	switch mode {
+	case "age":
+		return newAgeStore(configDir)
+	case "keyring":
+		return newKeyringStore()
+	default:
+		return nil, fmt.Errorf("unknown encryption mode %q", mode)
+	}
+}
+
+func resolveEncryptedStore(c *SSHConfig) (EncryptedStore, error) {
+	if c.EncryptionMode == "" {
+		return nil, fmt.Errorf("config encryption is not enabled; run 'gss init --encrypt age' or 'gss init --encrypt keyring'")
+	}
+	return newEncryptedStore(c.EncryptionMode, c.ConfigPath)
+}
+
+// ageStore encrypts config.json to <configDir>/recipients.txt and decrypts
+// it with <configDir>/identity.
+type ageStore struct {
+	recipients []age.Recipient
+	identities []age.Identity
+}
+
+func newAgeStore(configDir string) (*ageStore, error) {
+	recipientsData, err := os.ReadFile(filepath.Join(configDir, "recipients.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipients.txt: %w", err)
+	}
+	recipients, err := age.ParseRecipients(bytes.NewReader(recipientsData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recipients.txt: %w", err)
+	}
+
+	identityData, err := os.ReadFile(filepath.Join(configDir, "identity"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file: %w", err)
+	}
+	identities, err := age.ParseIdentities(bytes.NewReader(identityData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity file: %w", err)
+	}
+
+	return &ageStore{recipients: recipients, identities: identities}, nil
+}
+
+func (s *ageStore) Encrypt(plaintext []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, s.recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *ageStore) Decrypt(ciphertext []byte) ([]byte, error) {
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), s.identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// keyringStore AES-GCM-seals config.json under a random key held in the OS
+// keyring, prefixing ciphertext with keyringConfigMagic.
+type keyringStore struct {
+	key []byte
+}
+
+func newKeyringStore() (*keyringStore, error) {
+	encoded, err := keyring.Get(keyringService, "config-key")
+	if err != nil {
+		return nil, fmt.Errorf("no config encryption key found in OS keyring; run 'gss init --encrypt keyring' first: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode keyring config key: %w", err)
+	}
+	return &keyringStore{key: key}, nil
+}
+
+func (s *keyringStore) Encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return append([]byte(keyringConfigMagic), sealed...), nil
+}
+
+func (s *keyringStore) Decrypt(ciphertext []byte) ([]byte, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+	body := ciphertext[len(keyringConfigMagic):]
+	if len(body) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted config is truncated")
+	}
+	nonce, sealed := body[:gcm.NonceSize()], body[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func (s *keyringStore) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// setupAgeEncryption generates a fresh X25519 identity for config.json,
+// unless an identity/recipients.txt pair already exists in configDir (e.g.
+// synced in from another machine via a dotfiles repo) — in which case it
+// reuses them so it doesn't orphan anything already encrypted with them.
+// Pass force to regenerate anyway.
+func setupAgeEncryption(configDir string, force bool) error {
+	identityPath := filepath.Join(configDir, "identity")
+	recipientsPath := filepath.Join(configDir, "recipients.txt")
+
+	if !force {
+		if _, err := os.Stat(identityPath); err == nil {
+			if _, err := os.Stat(recipientsPath); err == nil {
+				return nil
+			}
+		}
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return fmt.Errorf("failed to generate age identity: %w", err)
+	}
+	if err := os.WriteFile(identityPath, []byte(identity.String()+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write identity file: %w", err)
+	}
+	if err := os.WriteFile(recipientsPath, []byte(identity.Recipient().String()+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write recipients.txt: %w", err)
+	}
+	return nil
+}
+
+// setupKeyringEncryption generates a fresh AES key and stores it in the OS
+// keyring, unless one is already stored there. Pass force to regenerate
+// anyway (this orphans anything encrypted with the old key).
+func setupKeyringEncryption(force bool) error {
+	if !force {
+		if _, err := keyring.Get(keyringService, "config-key"); err == nil {
+			return nil
+		}
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate config encryption key: %w", err)
+	}
+	if err := keyring.Set(keyringService, "config-key", base64.StdEncoding.EncodeToString(key)); err != nil {
+		return fmt.Errorf("failed to save config encryption key to OS keyring: %w", err)
+	}
+	return nil
+}
+
+func (c *SSHConfig) generateKeyPair(name, keyType string, bits int, passphrase string) {
+	if name == "" {
+		name = "id_rsa"
+	}
+	if keyType == "" {
+		keyType = "rsa"
+	}
+
+	privPath, pubPath := getUniqueFilePaths(c.ConfigPath, name)
+
+	// Convert to absolute paths
+	absPrivPath, err := filepath.Abs(privPath)
+	if err != nil {
+		fmt.Printf("Failed to convert private key path to absolute: %v\n", err)
+		os.Exit(1)
+	}
+	absPubPath, err := filepath.Abs(pubPath)
+	if err != nil {
+		fmt.Printf("Failed to convert public key path to absolute: %v\n", err)
+		os.Exit(1)
+	}
+
+	var privateKey interface{}
+	var bitsUsed int
+	switch keyType {
+	case "rsa":
+		if bits == 0 {
+			bits = 2048
+		}
+		rsaKey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			fmt.Printf("Failed to generate private key: %v\n", err)
+			os.Exit(1)
+		}
+		privateKey, bitsUsed = rsaKey, bits
+	case "ed25519":
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			fmt.Printf("Failed to generate private key: %v\n", err)
+			os.Exit(1)
+		}
+		privateKey, bitsUsed = edKey, 256
+	case "ecdsa":
+		curveBits := bits
+		if curveBits == 0 {
+			curveBits = 256
+		}
+		var curve elliptic.Curve
+		switch curveBits {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			fmt.Printf("Unsupported ECDSA bit size: %d (use 256, 384, or 521)\n", curveBits)
+			os.Exit(1)
+		}
+		ecKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			fmt.Printf("Failed to generate private key: %v\n", err)
+			os.Exit(1)
+		}
+		privateKey, bitsUsed = ecKey, curveBits
+	default:
+		fmt.Printf("Unsupported key type: %s (use rsa, ed25519, or ecdsa)\n", keyType)
+		os.Exit(1)
+	}
+
+	var privPEM *pem.Block
+	if passphrase != "" {
+		privPEM, err = ssh.MarshalPrivateKeyWithPassphrase(privateKey, name, []byte(passphrase))
+	} else {
+		privPEM, err = ssh.MarshalPrivateKey(privateKey, name)
+	}
+	if err != nil {
+		fmt.Printf("Failed to marshal private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	privFile, err := os.OpenFile(absPrivPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		fmt.Printf("Failed to create private key file: %v\n", err)
+		os.Exit(1)
+	}
+	defer privFile.Close()
+
+	if err := pem.Encode(privFile, privPEM); err != nil {
+		fmt.Printf("Failed to write private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	publicKey, err := publicKeyFromPrivate(privateKey)
+	if err != nil {
+		fmt.Printf("Failed to derive public key: %v\n", err)
+		os.Exit(1)
+	}
+	pubKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		fmt.Printf("Failed to generate public key: %v\n", err)
+		os.Exit(1)
+	}
+	pubBytes := ssh.MarshalAuthorizedKey(pubKey)
+
+	pubFile, err := os.OpenFile(absPubPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		fmt.Printf("Failed to create public key file: %v\n", err)
+		os.Exit(1)
+	}
+	defer pubFile.Close()
+
+	if _, err := pubFile.Write(pubBytes); err != nil {
+		fmt.Printf("Failed to write public key: %v\n", err)
+		os.Exit(1)
+	}
+
+	fingerprint := ssh.FingerprintSHA256(pubKey)
+
+	fmt.Printf("Generated: %s (%s, %s, %s, %s)\n", name, absPrivPath, absPubPath, keyType, fingerprint)
+	c.Keys = append(c.Keys, SSHKeyPairConfig{
+		Name:           name,
+		PrivateKeyPath: absPrivPath,
+		PublicKeyPath:  absPubPath,
+		SSHConfig:      "",
+		KeyType:        keyType,
+		Bits:           bitsUsed,
+		Fingerprint:    fingerprint,
+		GitConfig:      make(map[string]interface{}),
+	})
+}
+
+func publicKeyFromPrivate(privateKey interface{}) (interface{}, error) {
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
+	case ed25519.PrivateKey:
+		return k.Public(), nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+}
+
+func describeKey(privateKey interface{}) (string, int) {
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return "rsa", k.N.BitLen()
+	case ed25519.PrivateKey:
+		return "ed25519", 256
+	case *ecdsa.PrivateKey:
+		return "ecdsa", k.Curve.Params().BitSize
+	default:
+		return "unknown", 0
+	}
+}
+
+func (c *SSHConfig) importKeyPair(privPath, pubPath, name, sshConfigPath string, gitConfig map[string]interface{}) {
+	if _, err := os.Stat(privPath); os.IsNotExist(err) {
+		fmt.Printf("Private key not found: %s\n", privPath)
+		os.Exit(1)
+	}
+	if _, err := os.Stat(pubPath); os.IsNotExist(err) {
+		fmt.Printf("Public key not found: %s\n", pubPath)
+		os.Exit(1)
+	}
+
+	// Read private key to validate
+	privData, err := os.ReadFile(privPath)
+	if err != nil {
+		fmt.Printf("Failed to read private key: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -546,6 +1628,52 @@ func (c *SSHConfig) importKeyPair(privPath, pubPath, name, sshConfigPath string,
 		fmt.Println("Invalid private key format")
 		os.Exit(1)
 	}
+	switch privBlock.Type {
+	case "OPENSSH PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+	default:
+		fmt.Printf("Unsupported private key format: %s\n", privBlock.Type)
+		os.Exit(1)
+	}
+
+	pubData, err := os.ReadFile(pubPath)
+	if err != nil {
+		fmt.Printf("Failed to read public key: %v\n", err)
+		os.Exit(1)
+	}
+	storedPub, _, _, _, err := ssh.ParseAuthorizedKey(pubData)
+	if err != nil {
+		fmt.Printf("Failed to parse public key: %v\n", err)
+		os.Exit(1)
+	}
+
+	var keyType string
+	var bits int
+	var fingerprint string
+	raw, err := ssh.ParseRawPrivateKey(privData)
+	if _, missing := err.(*ssh.PassphraseMissingError); missing {
+		fmt.Println("Private key is passphrase-protected; skipping public/private key match check.")
+		fingerprint = ssh.FingerprintSHA256(storedPub)
+	} else if err != nil {
+		fmt.Printf("Failed to parse private key: %v\n", err)
+		os.Exit(1)
+	} else {
+		derivedPub, err := publicKeyFromPrivate(raw)
+		if err != nil {
+			fmt.Printf("Failed to derive public key: %v\n", err)
+			os.Exit(1)
+		}
+		sshPub, err := ssh.NewPublicKey(derivedPub)
+		if err != nil {
+			fmt.Printf("Failed to build public key: %v\n", err)
+			os.Exit(1)
+		}
+		if !bytes.Equal(sshPub.Marshal(), storedPub.Marshal()) {
+			fmt.Println("Public and private key do not match; refusing to import.")
+			os.Exit(1)
+		}
+		keyType, bits = describeKey(raw)
+		fingerprint = ssh.FingerprintSHA256(sshPub)
+	}
 
 	// Validate SSH config path if provided
 	var absSSHConfigPath string
@@ -563,6 +1691,9 @@ func (c *SSHConfig) importKeyPair(privPath, pubPath, name, sshConfigPath string,
 		PrivateKeyPath: privPath,
 		PublicKeyPath:  pubPath,
 		SSHConfig:      absSSHConfigPath,
+		KeyType:        keyType,
+		Bits:           bits,
+		Fingerprint:    fingerprint,
 		GitConfig:      gitConfig,
 	})
 }
@@ -590,6 +1721,12 @@ func (c *SSHConfig) listKeyPairs() {
 		fmt.Printf("  Private Key: %s\n", key.PrivateKeyPath)
 		fmt.Printf("  Public Key: %s\n", key.PublicKeyPath)
 		fmt.Printf("  SSH Config: %s\n", sshConfigPath)
+		if key.KeyType != "" {
+			fmt.Printf("  Type: %s (%d bits)\n", key.KeyType, key.Bits)
+		}
+		if key.Fingerprint != "" {
+			fmt.Printf("  Fingerprint: %s\n", key.Fingerprint)
+		}
 
 		if len(key.GitConfig) > 0 {
 			fmt.Println("  Git Config:")
@@ -632,3 +1769,219 @@ func getUniqueFilePaths(configPath, baseName string) (privPath, pubPath string)
 
 	return privPath, pubPath
 }
+
+// upsertManagedBlock swaps in the "# BEGIN gss:<name>" / "# END gss:<name>"
+// section, or appends it if the markers aren't found yet.
+func upsertManagedBlock(content, name, block string) string {
+	begin := fmt.Sprintf("# BEGIN gss:%s", name)
+	end := fmt.Sprintf("# END gss:%s", name)
+
+	lines := strings.Split(content, "\n")
+	startIdx, endIdx := -1, -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == begin {
+			startIdx = i
+		}
+		if strings.TrimSpace(line) == end && startIdx != -1 {
+			endIdx = i
+			break
+		}
+	}
+
+	newLines := strings.Split(strings.TrimRight(block, "\n"), "\n")
+	if startIdx != -1 && endIdx != -1 {
+		merged := append([]string{}, lines[:startIdx]...)
+		merged = append(merged, newLines...)
+		merged = append(merged, lines[endIdx+1:]...)
+		return strings.Join(merged, "\n")
+	}
+
+	trimmed := strings.TrimRight(content, "\n")
+	if trimmed == "" {
+		return strings.Join(newLines, "\n") + "\n"
+	}
+	return trimmed + "\n\n" + strings.Join(newLines, "\n") + "\n"
+}
+
+func renderHostBlock(name string, hb HostBlock, identityFile string) string {
+	alias := hb.Alias
+	if alias == "" {
+		alias = name
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# BEGIN gss:%s\n", name)
+	fmt.Fprintf(&b, "Host %s\n", alias)
+	if hb.HostName != "" {
+		fmt.Fprintf(&b, "  HostName %s\n", hb.HostName)
+	}
+	if hb.User != "" {
+		fmt.Fprintf(&b, "  User %s\n", hb.User)
+	}
+	fmt.Fprintf(&b, "  IdentityFile %s\n", identityFile)
+	fmt.Fprintf(&b, "  IdentitiesOnly yes\n")
+	fmt.Fprintf(&b, "# END gss:%s\n", name)
+	return b.String()
+}
+
+// resolveIdentityFile falls back from an explicit PrivateKeyPath to whatever
+// IdentityFile is already configured for the key's host aliases, and finally
+// to the usual default locations ssh itself would try.
+func resolveIdentityFile(key SSHKeyPairConfig, existingConfig string) (string, error) {
+	if key.PrivateKeyPath != "" {
+		return key.PrivateKeyPath, nil
+	}
+
+	if existingConfig != "" {
+		if cfg, err := sshconfig.Decode(strings.NewReader(existingConfig)); err == nil {
+			for _, hb := range key.Hosts {
+				if hb.Alias == "" {
+					continue
+				}
+				if v, err := cfg.Get(hb.Alias, "IdentityFile"); err == nil && v != "" {
+					return expandHome(v), nil
+				}
+			}
+		}
+	}
+
+	home := getHomeDir()
+	for _, candidate := range []string{
+		filepath.Join(home, ".ssh", "identity"),
+		filepath.Join(home, ".ssh", "id_rsa"),
+		filepath.Join(home, ".ssh", "id_ed25519"),
+	} {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("key %q has no private key path and none could be discovered", key.Name)
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(getHomeDir(), path[2:])
+	}
+	return path
+}
+
+type cachedKey struct {
+	raw    interface{}
+	signer ssh.Signer
+}
+
+var (
+	keyCacheMu sync.Mutex
+	keyCache   = map[string]cachedKey{}
+)
+
+// loadPrivateKey parses the key at path, prompting for a passphrase at most
+// once per process. If remember is set and the key is encrypted, the
+// passphrase is also saved to the OS keyring so future runs don't prompt.
+func loadPrivateKey(path string, remember bool) (cachedKey, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return cachedKey{}, fmt.Errorf("failed to resolve key path: %w", err)
+	}
+
+	keyCacheMu.Lock()
+	if ck, ok := keyCache[absPath]; ok {
+		keyCacheMu.Unlock()
+		return ck, nil
+	}
+	keyCacheMu.Unlock()
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return cachedKey{}, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	raw, err := ssh.ParseRawPrivateKey(data)
+	if _, missing := err.(*ssh.PassphraseMissingError); missing {
+		passphrase, perr := keyring.Get(keyringService, absPath)
+		if perr != nil || passphrase == "" {
+			passphrase, perr = promptPassphrase(fmt.Sprintf("Enter passphrase for %s: ", absPath))
+			if perr != nil {
+				return cachedKey{}, perr
+			}
+		}
+
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+		if err != nil {
+			return cachedKey{}, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+
+		if remember {
+			if serr := keyring.Set(keyringService, absPath, passphrase); serr != nil {
+				fmt.Printf("Warning: failed to save passphrase to OS keyring: %v\n", serr)
+			}
+		}
+	} else if err != nil {
+		return cachedKey{}, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(raw)
+	if err != nil {
+		return cachedKey{}, fmt.Errorf("failed to build signer: %w", err)
+	}
+
+	ck := cachedKey{raw: raw, signer: signer}
+	keyCacheMu.Lock()
+	keyCache[absPath] = ck
+	keyCacheMu.Unlock()
+	return ck, nil
+}
+
+func promptPassphrase(prompt string) (string, error) {
+	fmt.Print(prompt)
+	bytePassword, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(bytePassword), nil
+}
+
+// loadKeyIntoAgent adds key's private key to the running ssh-agent. If the
+// agent already holds the matching public key this is a no-op.
+func loadKeyIntoAgent(key SSHKeyPairConfig, identityFile string, remember bool) error {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return fmt.Errorf("SSH_AUTH_SOCK is not set; is ssh-agent running?")
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return fmt.Errorf("failed to connect to ssh-agent: %w", err)
+	}
+	defer conn.Close()
+	ag := agent.NewClient(conn)
+
+	if key.PublicKeyPath != "" {
+		if pubData, err := os.ReadFile(key.PublicKeyPath); err == nil {
+			if pubKey, _, _, _, err := ssh.ParseAuthorizedKey(pubData); err == nil {
+				if identities, err := ag.List(); err == nil {
+					for _, id := range identities {
+						if bytes.Equal(id.Marshal(), pubKey.Marshal()) {
+							fmt.Printf("Key %s is already loaded in ssh-agent.\n", key.Name)
+							return nil
+						}
+					}
+				}
+			}
+		}
+	}
+
+	ck, err := loadPrivateKey(identityFile, remember)
+	if err != nil {
+		return err
+	}
+
+	if err := ag.Add(agent.AddedKey{PrivateKey: ck.raw, Comment: key.Name}); err != nil {
+		return fmt.Errorf("failed to add key to ssh-agent: %w", err)
+	}
+
+	fmt.Printf("Loaded key %s into ssh-agent.\n", key.Name)
+	return nil
+}